@@ -0,0 +1,93 @@
+// Spyderbat Event Forwarder
+// Copyright (C) 2022-2023 Spyderbat, Inc.
+// Use according to license terms.
+
+// Package config loads the forwarder's YAML configuration file.
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds all user-configurable forwarder settings.
+type Config struct {
+	OrgUID  string `yaml:"org_uid"`
+	APIKey  string `yaml:"api_key"`
+	APIHost string `yaml:"api_host"`
+	UIUrl   string `yaml:"ui_url"`
+
+	LogPath               string `yaml:"log_path"`
+	StdOut                bool   `yaml:"stdout"`
+	LocalSyslogForwarding bool   `yaml:"local_syslog_forwarding"`
+
+	FilterExpression []string `yaml:"filter_expression"`
+	Linkback         bool     `yaml:"linkback"`
+
+	// LogFormat is "text" or "json"; LogLevel is any level
+	// (slog.Level).UnmarshalText accepts, e.g. "debug", "info", "warn".
+	// Both govern operator/diagnostic logs only, not forwarded events.
+	LogFormat string `yaml:"log_format"`
+	LogLevel  string `yaml:"log_level"`
+
+	// Sinks configures where forwarded events are written. If empty, the
+	// forwarder falls back to LogPath/StdOut/LocalSyslogForwarding above
+	// so existing configs keep working unchanged.
+	Sinks []SinkConfig `yaml:"sinks"`
+
+	// MetricsListen is the bind address for the /metrics, /healthz, and
+	// /readyz HTTP server (e.g. ":9090"). Metrics are disabled if empty.
+	MetricsListen string `yaml:"metrics_listen"`
+}
+
+// SinkConfig describes a single output sink. Which fields apply depends on
+// Type; see the sink package for the sinks it knows how to build.
+type SinkConfig struct {
+	Type string `yaml:"type"`
+
+	// file
+	Filename   string `yaml:"filename"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+
+	// kafka
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+
+	// webhook
+	URL           string            `yaml:"url"`
+	Headers       map[string]string `yaml:"headers"`
+	FlushInterval time.Duration     `yaml:"flush_interval"`
+	FlushBytes    int               `yaml:"flush_bytes"`
+
+	// tcp
+	Listen        string `yaml:"listen"`
+	ClientBufSize int    `yaml:"client_buf_size"`
+}
+
+// LoadConfig reads and parses the YAML config file at path. logger is used
+// to report the settings that were loaded; callers typically pass a
+// bootstrap logger here, since the final logger's format/level usually
+// come from the config being loaded.
+func LoadConfig(path string, logger *slog.Logger) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	cfg := new(Config)
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	logger.Info("loaded config",
+		"org_uid", cfg.OrgUID,
+		"api_host", cfg.APIHost,
+		"log_path", cfg.LogPath,
+		"local_syslog_forwarding", cfg.LocalSyslogForwarding,
+	)
+	return cfg, nil
+}
@@ -0,0 +1,55 @@
+// Spyderbat Event Forwarder
+// Copyright (C) 2022-2023 Spyderbat, Inc.
+// Use according to license terms.
+
+// Package cursor persists the poll loop's resume point to disk, so a
+// restart can pick up where it left off instead of rescanning history.
+package cursor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"spyderbat-event-forwarder/record"
+)
+
+const filename = "cursor.json"
+
+// Cursor is the durable resume point for the poll loop: the last
+// successfully-forwarded record's opaque since token and its timestamp.
+type Cursor struct {
+	Since string            `json:"since"`
+	Time  record.RecordTime `json:"time"`
+}
+
+// Load reads the cursor file from logPath. Callers should treat any error
+// (missing file, corrupt JSON) as "no cursor" and fall back to another
+// resume strategy.
+func Load(logPath string) (*Cursor, error) {
+	b, err := os.ReadFile(filepath.Join(logPath, filename))
+	if err != nil {
+		return nil, err
+	}
+	c := new(Cursor)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save atomically writes c to the cursor file in logPath, via a temp file
+// plus rename so a crash mid-write never leaves a corrupt cursor behind.
+func Save(logPath string, c *Cursor) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(logPath, filename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
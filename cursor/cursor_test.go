@@ -0,0 +1,30 @@
+package cursor
+
+import (
+	"testing"
+
+	"spyderbat-event-forwarder/record"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	want := &Cursor{Since: "abc123", Time: record.RecordTime(1700000000.5)}
+	if err := Save(dir, want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.Since != want.Since || got.Time != want.Time {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Fatal("expected an error loading a cursor that doesn't exist")
+	}
+}
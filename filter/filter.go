@@ -0,0 +1,132 @@
+// Spyderbat Event Forwarder
+// Copyright (C) 2022-2023 Spyderbat, Inc.
+// Use according to license terms.
+
+// Package filter evaluates user-supplied expr expressions against decoded
+// event records, replacing per-line regexp matching against raw JSON.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+)
+
+// Env is the expression environment every filter expression is evaluated
+// against. Fields are tagged so expressions can use the same lowercase,
+// JSON-style names the rest of the config uses (e.g. "severity", not
+// "Severity"); anything not modeled explicitly is still reachable through
+// features for schema-specific data.
+type Env struct {
+	Schema   string         `expr:"schema"`
+	Severity float64        `expr:"severity"`
+	MUID     string         `expr:"muid"`
+	ID       string         `expr:"id"`
+	Time     float64        `expr:"time"`
+	Features map[string]any `expr:"features"`
+}
+
+// Result is what evaluating a filter expression against a record produces.
+// An expression normally evaluates to a bare bool; it may instead evaluate
+// to a map with an "allow" key, setting synthetic fields alongside it (e.g.
+// "linkback_required") for downstream code to consult. See forwarder.go's
+// addLinkback call.
+type Result struct {
+	Allow            bool
+	LinkbackRequired bool
+}
+
+// Engine evaluates a set of compiled filter expressions against decoded
+// event records.
+type Engine struct {
+	programs []*vm.Program
+	pool     sync.Pool
+}
+
+// New compiles each of the given expr expressions against Env and returns
+// an Engine. Expressions are type-checked here so a bad filter fails fast
+// at startup rather than silently dropping every event later.
+func New(expressions []string) (*Engine, error) {
+	e := &Engine{
+		pool: sync.Pool{New: func() any { return new(Env) }},
+	}
+	for _, src := range expressions {
+		program, err := expr.Compile(src, expr.Env(Env{}))
+		if err != nil {
+			return nil, fmt.Errorf("compiling filter expression %q: %w", src, err)
+		}
+		e.programs = append(e.programs, program)
+	}
+	return e, nil
+}
+
+// Allow reports whether record passes at least one of the engine's filter
+// expressions (the same any-of-N semantics the old per-regex loop had),
+// along with any synthetic fields the matching expression set. An Engine
+// with no expressions allows everything through.
+func (e *Engine) Allow(record []byte) Result {
+	if len(e.programs) == 0 {
+		return Result{Allow: true}
+	}
+
+	env := e.pool.Get().(*Env)
+	defer e.pool.Put(env)
+	if !decodeEnv(record, env) {
+		return Result{}
+	}
+
+	for _, program := range e.programs {
+		out, err := expr.Run(program, *env)
+		if err != nil {
+			continue
+		}
+		if result, matched := resultOf(out); matched {
+			return result
+		}
+	}
+	return Result{}
+}
+
+// resultOf interprets a single expression's output. A bare bool is a plain
+// allow/deny; a map lets the expression set synthetic fields (currently
+// "linkback_required") alongside "allow".
+func resultOf(out any) (Result, bool) {
+	switch v := out.(type) {
+	case bool:
+		if v {
+			return Result{Allow: true}, true
+		}
+	case map[string]any:
+		if allow, _ := v["allow"].(bool); allow {
+			linkback, _ := v["linkback_required"].(bool)
+			return Result{Allow: true, LinkbackRequired: linkback}, true
+		}
+	}
+	return Result{}, false
+}
+
+// decodeEnv populates env from a raw event record, resetting any fields
+// left over from a previous use out of the pool.
+func decodeEnv(record []byte, env *Env) bool {
+	var raw struct {
+		Schema   string         `json:"schema"`
+		Severity float64        `json:"severity"`
+		MUID     string         `json:"muid"`
+		ID       string         `json:"id"`
+		Time     float64        `json:"time"`
+		Features map[string]any `json:"features"`
+	}
+	if err := json.Unmarshal(record, &raw); err != nil {
+		return false
+	}
+	env.Schema = raw.Schema
+	env.Severity = raw.Severity
+	env.MUID = raw.MUID
+	env.ID = raw.ID
+	env.Time = raw.Time
+	env.Features = raw.Features
+	return true
+}
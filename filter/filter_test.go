@@ -0,0 +1,67 @@
+package filter
+
+import "testing"
+
+func TestAllow(t *testing.T) {
+	cases := []struct {
+		name         string
+		exprs        []string
+		record       string
+		wantAllow    bool
+		wantLinkback bool
+	}{
+		{
+			name:      "no expressions allows everything",
+			exprs:     nil,
+			record:    `{"schema":"event_redflag::x","severity":1}`,
+			wantAllow: true,
+		},
+		{
+			name:      "lowercase field names resolve",
+			exprs:     []string{`severity >= 7 && schema startsWith "event_redflag::" && !(features.interactive)`},
+			record:    `{"schema":"event_redflag::x","severity":8,"features":{"interactive":false}}`,
+			wantAllow: true,
+		},
+		{
+			name:      "non-matching expression denies",
+			exprs:     []string{`severity >= 7`},
+			record:    `{"schema":"x","severity":1}`,
+			wantAllow: false,
+		},
+		{
+			name:      "matches operator",
+			exprs:     []string{`schema matches "^event_redflag::"`},
+			record:    `{"schema":"event_redflag::x","severity":1}`,
+			wantAllow: true,
+		},
+		{
+			name:         "map result can set linkback_required",
+			exprs:        []string{`severity >= 9 ? {"allow": true, "linkback_required": true} : false`},
+			record:       `{"schema":"x","severity":9}`,
+			wantAllow:    true,
+			wantLinkback: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := New(tc.exprs)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			got := e.Allow([]byte(tc.record))
+			if got.Allow != tc.wantAllow {
+				t.Errorf("Allow = %v, want %v", got.Allow, tc.wantAllow)
+			}
+			if got.LinkbackRequired != tc.wantLinkback {
+				t.Errorf("LinkbackRequired = %v, want %v", got.LinkbackRequired, tc.wantLinkback)
+			}
+		})
+	}
+}
+
+func TestNewInvalidExpression(t *testing.T) {
+	if _, err := New([]string{"this is not valid expr"}); err == nil {
+		t.Fatal("expected a compile error, got nil")
+	}
+}
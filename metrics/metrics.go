@@ -0,0 +1,113 @@
+// Spyderbat Event Forwarder
+// Copyright (C) 2022-2023 Spyderbat, Inc.
+// Use according to license terms.
+
+// Package metrics exposes the forwarder's Prometheus metrics and a
+// /healthz + /readyz HTTP server for orchestrators.
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Counters, histograms, and gauges the poll loop and sinks report to.
+var (
+	EventsRetrievedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "events_retrieved_total",
+		Help: "Total event records retrieved from the backend.",
+	})
+	EventsForwardedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "events_forwarded_total",
+		Help: "Total event records forwarded to sinks.",
+	})
+	EventsDedupedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "events_deduped_total",
+		Help: "Total event records dropped as duplicates.",
+	})
+	EventsInvalidTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "events_invalid_total",
+		Help: "Total records that failed JSON validation.",
+	})
+	SinkErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sink_errors_total",
+		Help: "Total sink write errors, by sink.",
+	}, []string{"sink"})
+
+	SourceDataQueryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "source_data_query_duration_seconds",
+		Help: "Duration of SourceDataQuery calls to the backend.",
+	})
+	SinkWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "sink_write_duration_seconds",
+		Help: "Duration of sink writes, by sink.",
+	}, []string{"sink"})
+
+	LastEventAgeSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "last_event_age_seconds",
+		Help: "Age, at the end of the latest poll window, of the newest event the resume point has advanced to.",
+	})
+	SourcesTracked = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sources_tracked",
+		Help: "Number of data sources currently tracked for the org.",
+	})
+	LRUSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "lru_size",
+		Help: "Number of entries currently held in the dedup LRU.",
+	})
+)
+
+// Server exposes /metrics, /healthz, and /readyz over HTTP.
+type Server struct {
+	srv    *http.Server
+	ready  atomic.Bool
+	logger *slog.Logger
+}
+
+// NewServer builds a metrics server bound to listen. Call Start to run it.
+func NewServer(listen string, logger *slog.Logger) *Server {
+	s := &Server{logger: logger}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !s.ready.Load() {
+			http.Error(w, "not ready: no successful source data query yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	s.srv = &http.Server{Addr: listen, Handler: mux}
+	return s
+}
+
+// Start runs the server in the background. Listen errors are logged
+// rather than returned, since metrics are diagnostic and shouldn't take
+// down the forwarder.
+func (s *Server) Start() {
+	go func() {
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Warn("metrics server stopped", "err", err)
+		}
+	}()
+}
+
+// MarkReady flips /readyz to report healthy. Call it once the first
+// successful SourceDataQuery completes.
+func (s *Server) MarkReady() {
+	s.ready.Store(true)
+}
+
+// Close shuts the metrics server down.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
@@ -0,0 +1,27 @@
+// Spyderbat Event Forwarder
+// Copyright (C) 2022-2023 Spyderbat, Inc.
+// Use according to license terms.
+
+// Package record describes the minimal shape the forwarder needs to decode
+// out of every event line.
+package record
+
+import "time"
+
+// RecordTime is a spyderbat event timestamp, encoded by the backend as a
+// unix time in fractional seconds.
+type RecordTime float64
+
+// Time converts a RecordTime into a standard time.Time.
+func (t RecordTime) Time() time.Time {
+	sec := int64(t)
+	nsec := int64((float64(t) - float64(sec)) * float64(time.Second))
+	return time.Unix(sec, nsec)
+}
+
+// Record is the subset of fields the forwarder decodes out of every event
+// line in order to track recency, de-duplicate, and resume from a cursor.
+type Record struct {
+	ID   string     `json:"id"`
+	Time RecordTime `json:"time"`
+}
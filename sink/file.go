@@ -0,0 +1,45 @@
+package sink
+
+import (
+	"context"
+
+	"spyderbat-event-forwarder/config"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// fileSink writes records to a self-rotating log file on disk.
+type fileSink struct {
+	logger *lumberjack.Logger
+}
+
+func newFileSink(sc config.SinkConfig) Sink {
+	maxSize := sc.MaxSizeMB
+	if maxSize == 0 {
+		maxSize = 10 // megabytes after which a new file is created
+	}
+	maxBackups := sc.MaxBackups
+	if maxBackups == 0 {
+		maxBackups = 5
+	}
+	return &fileSink{
+		logger: &lumberjack.Logger{
+			Filename:   sc.Filename,
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+		},
+	}
+}
+
+func (s *fileSink) Write(_ context.Context, record []byte) error {
+	_, err := s.logger.Write(append(append([]byte{}, record...), '\n'))
+	return err
+}
+
+func (s *fileSink) Close() error {
+	return s.logger.Close()
+}
+
+func (s *fileSink) Name() string {
+	return "file"
+}
@@ -0,0 +1,36 @@
+package sink
+
+import (
+	"context"
+
+	"spyderbat-event-forwarder/config"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaSink publishes each record as a Kafka message.
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSink(sc config.SinkConfig) Sink {
+	return &kafkaSink{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(sc.Brokers...),
+			Topic:    sc.Topic,
+			Balancer: &kafka.LeastBytes{},
+		},
+	}
+}
+
+func (s *kafkaSink) Write(ctx context.Context, record []byte) error {
+	return s.writer.WriteMessages(ctx, kafka.Message{Value: record})
+}
+
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
+
+func (s *kafkaSink) Name() string {
+	return "kafka"
+}
@@ -0,0 +1,130 @@
+// Spyderbat Event Forwarder
+// Copyright (C) 2022-2023 Spyderbat, Inc.
+// Use according to license terms.
+
+// Package sink defines the pluggable output sinks forwarded events are
+// written to, and builds them from configuration.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"spyderbat-event-forwarder/config"
+	"spyderbat-event-forwarder/metrics"
+)
+
+// Sink is a destination for forwarded event records.
+type Sink interface {
+	// Write forwards a single event record. Implementations that batch
+	// internally may return before the record has actually been
+	// flushed downstream.
+	Write(ctx context.Context, record []byte) error
+	Close() error
+
+	// Name identifies the sink's type for metrics labels (e.g. "file",
+	// "kafka").
+	Name() string
+}
+
+// Build constructs the sinks described by cfgs, in order. If cfgs is
+// empty, it falls back to the legacy file/stdout/syslog sinks driven by
+// the top-level LogPath/StdOut/LocalSyslogForwarding settings, so old
+// configs keep working unchanged. logger is used by sinks that need to
+// report failures that can't be returned through Write, such as the
+// webhook sink's background flush.
+func Build(cfg *config.Config, logger *slog.Logger) ([]Sink, error) {
+	cfgs := cfg.Sinks
+	if len(cfgs) == 0 {
+		return buildDefaults(cfg)
+	}
+
+	sinks := make([]Sink, 0, len(cfgs))
+	for _, sc := range cfgs {
+		s, err := build(sc, logger)
+		if err != nil {
+			// close everything we already opened before giving up
+			for _, opened := range sinks {
+				opened.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+func build(sc config.SinkConfig, logger *slog.Logger) (Sink, error) {
+	switch sc.Type {
+	case "file":
+		return newFileSink(sc), nil
+	case "stdout":
+		return newStdoutSink(), nil
+	case "syslog":
+		return newSyslogSink()
+	case "kafka":
+		return newKafkaSink(sc), nil
+	case "webhook":
+		return newWebhookSink(sc, logger), nil
+	case "tcp":
+		return newTCPSink(sc)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", sc.Type)
+	}
+}
+
+func buildDefaults(cfg *config.Config) ([]Sink, error) {
+	var sinks []Sink
+	sinks = append(sinks, newFileSink(config.SinkConfig{
+		Filename: filepath.Join(cfg.LogPath, "spyderbat_events.log"),
+	}))
+
+	if cfg.StdOut {
+		sinks = append(sinks, newStdoutSink())
+	}
+
+	if cfg.LocalSyslogForwarding {
+		s, err := newSyslogSink()
+		if err != nil {
+			// syslog forwarding is best-effort, as it always has been
+			return sinks, nil
+		}
+		sinks = append(sinks, s)
+	}
+
+	return sinks, nil
+}
+
+// WriteAll writes record to every sink, recording per-sink write duration
+// and error metrics, and returns the first error encountered (after
+// attempting all of them).
+func WriteAll(ctx context.Context, sinks []Sink, record []byte) error {
+	var firstErr error
+	for _, s := range sinks {
+		start := time.Now()
+		err := s.Write(ctx, record)
+		metrics.SinkWriteDuration.WithLabelValues(s.Name()).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.SinkErrorsTotal.WithLabelValues(s.Name()).Inc()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// CloseAll closes every sink, returning the first error encountered (after
+// attempting all of them).
+func CloseAll(sinks []Sink) error {
+	var firstErr error
+	for _, s := range sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,60 @@
+package sink
+
+import (
+	"net"
+	"testing"
+
+	"spyderbat-event-forwarder/config"
+)
+
+func TestBuildFallsBackToLegacyDefaults(t *testing.T) {
+	cfg := &config.Config{LogPath: t.TempDir(), StdOut: true}
+
+	sinks, err := Build(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer CloseAll(sinks)
+
+	if len(sinks) != 2 {
+		t.Fatalf("got %d sinks, want 2 (file, stdout)", len(sinks))
+	}
+	if sinks[0].Name() != "file" || sinks[1].Name() != "stdout" {
+		t.Errorf("got sinks %q, %q; want file, stdout", sinks[0].Name(), sinks[1].Name())
+	}
+}
+
+func TestBuildUnknownSinkType(t *testing.T) {
+	cfg := &config.Config{Sinks: []config.SinkConfig{{Type: "bogus"}}}
+
+	if _, err := Build(cfg, testLogger()); err == nil {
+		t.Fatal("expected an error for an unknown sink type")
+	}
+}
+
+func TestBuildClosesAlreadyOpenedSinksOnError(t *testing.T) {
+	// find a free port for the tcp sink, then release it
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := &config.Config{Sinks: []config.SinkConfig{
+		{Type: "stdout"},
+		{Type: "tcp", Listen: addr},
+		{Type: "bogus"},
+	}}
+
+	if _, err := Build(cfg, testLogger()); err == nil {
+		t.Fatal("expected an error for an unknown sink type")
+	}
+
+	// the tcp sink opened before the error must have released its port
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("tcp sink was not closed: %v", err)
+	}
+	ln.Close()
+}
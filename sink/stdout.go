@@ -0,0 +1,26 @@
+package sink
+
+import (
+	"context"
+	"os"
+)
+
+// stdoutSink writes records to stdout, one per line.
+type stdoutSink struct{}
+
+func newStdoutSink() Sink {
+	return stdoutSink{}
+}
+
+func (stdoutSink) Write(_ context.Context, record []byte) error {
+	_, err := os.Stdout.Write(append(append([]byte{}, record...), '\n'))
+	return err
+}
+
+func (stdoutSink) Close() error {
+	return nil
+}
+
+func (stdoutSink) Name() string {
+	return "stdout"
+}
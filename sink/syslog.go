@@ -0,0 +1,31 @@
+package sink
+
+import (
+	"context"
+	"log/syslog"
+)
+
+// syslogSink forwards records to the local syslog daemon.
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func newSyslogSink() (Sink, error) {
+	w, err := syslog.Dial("", "", syslog.LOG_ALERT, "spyderbat-event")
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) Write(_ context.Context, record []byte) error {
+	return s.w.Alert(string(record))
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
+
+func (s *syslogSink) Name() string {
+	return "syslog"
+}
@@ -0,0 +1,115 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+
+	"spyderbat-event-forwarder/config"
+)
+
+const defaultClientBufSize = 256
+
+// tcpSink accepts long-lived subscriber connections on Listen and fans out
+// every record to all of them. A client whose buffer is full (it isn't
+// reading fast enough) is disconnected rather than allowed to block the
+// forwarder.
+type tcpSink struct {
+	ln net.Listener
+
+	mu      sync.Mutex
+	clients map[*tcpClient]struct{}
+}
+
+type tcpClient struct {
+	conn net.Conn
+	ch   chan []byte
+}
+
+func newTCPSink(sc config.SinkConfig) (Sink, error) {
+	ln, err := net.Listen("tcp", sc.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	bufSize := sc.ClientBufSize
+	if bufSize == 0 {
+		bufSize = defaultClientBufSize
+	}
+
+	s := &tcpSink{
+		ln:      ln,
+		clients: make(map[*tcpClient]struct{}),
+	}
+	go s.acceptLoop(bufSize)
+	return s, nil
+}
+
+func (s *tcpSink) acceptLoop(bufSize int) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		c := &tcpClient{conn: conn, ch: make(chan []byte, bufSize)}
+		s.mu.Lock()
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+		go s.serve(c)
+	}
+}
+
+func (s *tcpSink) serve(c *tcpClient) {
+	defer s.drop(c)
+	w := bufio.NewWriter(c.conn)
+	for record := range c.ch {
+		if _, err := w.Write(record); err != nil {
+			return
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *tcpSink) drop(c *tcpClient) {
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	c.conn.Close()
+}
+
+func (s *tcpSink) Write(_ context.Context, record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c.ch <- record:
+		default:
+			// slow consumer: disconnect rather than block the forwarder
+			close(c.ch)
+			delete(s.clients, c)
+			c.conn.Close()
+		}
+	}
+	return nil
+}
+
+func (s *tcpSink) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for c := range s.clients {
+		close(c.ch)
+		delete(s.clients, c)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+func (s *tcpSink) Name() string {
+	return "tcp"
+}
@@ -0,0 +1,118 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"spyderbat-event-forwarder/config"
+)
+
+func TestTCPSinkDropsSlowConsumerWithoutBlockingOthers(t *testing.T) {
+	sAny, err := newTCPSink(config.SinkConfig{Listen: "127.0.0.1:0", ClientBufSize: 2})
+	if err != nil {
+		t.Fatalf("newTCPSink: %v", err)
+	}
+	s := sAny.(*tcpSink)
+	defer s.Close()
+
+	// slow: a client whose serve() goroutine will block forever on its
+	// first write, since net.Pipe is synchronous and nothing ever reads
+	// the other end. Once its bounded channel fills up, Write must drop
+	// it rather than block.
+	slowConn, slowRemote := net.Pipe()
+	defer slowRemote.Close()
+	slow := &tcpClient{conn: slowConn, ch: make(chan []byte, 2)}
+
+	s.mu.Lock()
+	s.clients[slow] = struct{}{}
+	s.mu.Unlock()
+	go s.serve(slow)
+
+	// fast: a real connection accepted through the sink's own listener,
+	// continuously drained over an actual socket, so it should keep
+	// receiving records and never get dropped.
+	fastConn, err := net.Dial("tcp", s.ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer fastConn.Close()
+
+	received := make(chan string, 32)
+	go func() {
+		sc := bufio.NewScanner(fastConn)
+		for sc.Scan() {
+			received <- sc.Text()
+		}
+	}()
+
+	// wait for acceptLoop to register the fast client alongside slow
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.clients)
+		s.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("fast client was never registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			if err := s.Write(context.Background(), []byte("msg")); err != nil {
+				t.Errorf("Write: %v", err)
+			}
+			// give the real, fast consumer's serve goroutine a chance to
+			// drain each record over the loopback socket; the slow
+			// consumer never drains regardless of pacing, so it still
+			// overflows its 2-slot buffer well within these 5 writes.
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Write blocked on the slow consumer instead of dropping it")
+	}
+
+	s.mu.Lock()
+	_, slowStillPresent := s.clients[slow]
+	remaining := len(s.clients)
+	s.mu.Unlock()
+
+	if slowStillPresent {
+		t.Error("slow consumer was not dropped")
+	}
+	if remaining != 1 {
+		t.Errorf("got %d remaining clients, want 1 (the fast consumer)", remaining)
+	}
+
+	select {
+	case got := <-received:
+		if got != "msg" {
+			t.Errorf("fast consumer received %q, want %q", got, "msg")
+		}
+	case <-time.After(time.Second):
+		t.Error("fast consumer never received a record")
+	}
+}
+
+func TestTCPSinkName(t *testing.T) {
+	sAny, err := newTCPSink(config.SinkConfig{Listen: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("newTCPSink: %v", err)
+	}
+	defer sAny.Close()
+	if got := sAny.Name(); got != "tcp" {
+		t.Errorf("Name() = %q, want %q", got, "tcp")
+	}
+}
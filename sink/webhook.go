@@ -0,0 +1,171 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"spyderbat-event-forwarder/config"
+	"spyderbat-event-forwarder/metrics"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	defaultFlushInterval = 5 * time.Second
+	defaultFlushBytes    = 64 * 1024
+
+	// requestTimeout bounds a single POST attempt, and retryMax/retryWait
+	// bound retryablehttp's total backoff, so a wedged or erroring endpoint
+	// can only ever delay the background flush loop, never the caller of
+	// Write.
+	requestTimeout = 10 * time.Second
+	retryMax       = 2
+	retryWaitMin   = 200 * time.Millisecond
+	retryWaitMax   = 2 * time.Second
+)
+
+// webhookSink batches records and POSTs them as newline-delimited JSON to
+// a URL, retrying transient failures via retryablehttp.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	client  *retryablehttp.Client
+	logger  *slog.Logger
+
+	flushInterval time.Duration
+	flushBytes    int
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	flushCh chan struct{}
+	closeCh chan struct{}
+	doneCh  chan struct{}
+}
+
+func newWebhookSink(sc config.SinkConfig, logger *slog.Logger) Sink {
+	flushInterval := sc.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+	flushBytes := sc.FlushBytes
+	if flushBytes <= 0 {
+		flushBytes = defaultFlushBytes
+	}
+
+	client := retryablehttp.NewClient()
+	client.Logger = nil
+	client.HTTPClient.Timeout = requestTimeout
+	client.RetryMax = retryMax
+	client.RetryWaitMin = retryWaitMin
+	client.RetryWaitMax = retryWaitMax
+
+	s := &webhookSink{
+		url:           sc.URL,
+		headers:       sc.Headers,
+		client:        client,
+		logger:        logger.With("sink", "webhook"),
+		flushInterval: flushInterval,
+		flushBytes:    flushBytes,
+		flushCh:       make(chan struct{}, 1),
+		closeCh:       make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s
+}
+
+// Write appends record to the current batch. The actual POST always
+// happens on the background flush loop, whether triggered by the flush
+// interval or by the buffer crossing flushBytes here, so a slow or down
+// endpoint never blocks the caller (the main poll loop, via sink.WriteAll).
+func (s *webhookSink) Write(_ context.Context, record []byte) error {
+	s.mu.Lock()
+	s.buf.Write(record)
+	s.buf.WriteByte('\n')
+	overThreshold := s.buf.Len() >= s.flushBytes
+	s.mu.Unlock()
+
+	if overThreshold {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+			// a flush is already pending; it'll pick up this data too
+		}
+	}
+	return nil
+}
+
+func (s *webhookSink) flushLoop() {
+	defer close(s.doneCh)
+	t := time.NewTicker(s.flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.mu.Lock()
+			s.flushLocked()
+			s.mu.Unlock()
+		case <-s.flushCh:
+			s.mu.Lock()
+			s.flushLocked()
+			s.mu.Unlock()
+		case <-s.closeCh:
+			s.mu.Lock()
+			s.flushLocked()
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// flushLocked POSTs the current batch and resets it. s.mu must be held on
+// entry; it is released while the POST is in flight, so Write can keep
+// appending, and held again on return.
+// It only ever runs on the flush loop, never inline from Write, so
+// failures can't be returned to a caller; they're logged and counted
+// directly instead, the same way sink.WriteAll does for synchronous sinks.
+func (s *webhookSink) flushLocked() {
+	if s.buf.Len() == 0 {
+		return
+	}
+	body := append([]byte(nil), s.buf.Bytes()...)
+	s.buf.Reset()
+	s.mu.Unlock()
+	defer s.mu.Lock()
+
+	req, err := retryablehttp.NewRequest("POST", s.url, body)
+	if err != nil {
+		s.logger.Warn("building webhook request failed", "err", err)
+		metrics.SinkErrorsTotal.WithLabelValues("webhook").Inc()
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.logger.Warn("webhook request failed", "err", err)
+		metrics.SinkErrorsTotal.WithLabelValues("webhook").Inc()
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		s.logger.Warn("webhook returned non-2xx status", "status", resp.Status)
+		metrics.SinkErrorsTotal.WithLabelValues("webhook").Inc()
+	}
+}
+
+func (s *webhookSink) Close() error {
+	close(s.closeCh)
+	<-s.doneCh
+	return nil
+}
+
+func (s *webhookSink) Name() string {
+	return "webhook"
+}
@@ -0,0 +1,98 @@
+package sink
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"spyderbat-event-forwarder/config"
+	"spyderbat-event-forwarder/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func errCountFor(sinkName string) float64 {
+	return testutil.ToFloat64(metrics.SinkErrorsTotal.WithLabelValues(sinkName))
+}
+
+func TestWebhookSinkFlushesOnClose(t *testing.T) {
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newWebhookSink(config.SinkConfig{URL: srv.URL, FlushInterval: time.Hour}, testLogger())
+	if err := s.Write(nil, []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if string(got) != "{\"a\":1}\n" {
+		t.Errorf("server received %q", got)
+	}
+}
+
+func TestWebhookSinkWriteDoesNotBlockOnSlowEndpoint(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-block
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// flushBytes is crossed by the very first write, so Write triggers a
+	// flush attempt against an endpoint that won't respond until `block`
+	// is closed below, well after the deadline this test checks.
+	s := newWebhookSink(config.SinkConfig{URL: srv.URL, FlushInterval: time.Hour, FlushBytes: 1}, testLogger())
+
+	done := make(chan error, 1)
+	go func() { done <- s.Write(nil, []byte(`{"a":1}`)) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Write blocked on a slow webhook endpoint")
+	}
+
+	close(block)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestWebhookSinkCountsNon2xxAsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	before := errCountFor("webhook")
+
+	s := newWebhookSink(config.SinkConfig{URL: srv.URL, FlushInterval: time.Hour}, testLogger())
+	if err := s.Write(nil, []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if after := errCountFor("webhook"); after <= before {
+		t.Errorf("sink_errors_total{webhook} did not increase: before=%v after=%v", before, after)
+	}
+}
@@ -10,27 +10,28 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"io/fs"
-	"log"
-	"log/syslog"
+	"log/slog"
 	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"spyderbat-event-forwarder/api"
 	"spyderbat-event-forwarder/config"
+	"spyderbat-event-forwarder/cursor"
+	"spyderbat-event-forwarder/filter"
+	"spyderbat-event-forwarder/metrics"
 	"spyderbat-event-forwarder/record"
+	"spyderbat-event-forwarder/sink"
 
 	"github.com/golang/groupcache/lru"
 	"github.com/valyala/fastjson"
 	"golang.org/x/crypto/blake2b"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
@@ -45,13 +46,28 @@ const (
 // The hash key is a hash of the log data. There is no value.
 var lruCache = lru.New(dedupCacheElements)
 
-// loadState seeds the LRU from events already written to disk. It returns the most recent event time.
-func loadState(LogPath string) (record.RecordTime, error) {
+// loadState determines where to resume polling from. It prefers the
+// durable cursor file, since that's an O(1) read regardless of how much
+// history is retained on disk; it falls back to the old full-log walk
+// only if the cursor is missing or corrupt.
+func loadState(logPath string, logger *slog.Logger) (record.RecordTime, string, error) {
+	if c, err := cursor.Load(logPath); err == nil {
+		return c.Time, c.Since, nil
+	}
+	lastTime, err := scanLogDir(logPath, logger)
+	return lastTime, "", err
+}
+
+// scanLogDir walks every spyderbat_events*.log file, seeding the LRU (kept
+// as a secondary defense against backend-side replay) and returning the
+// most recent event time found. This is the pre-cursor resume strategy,
+// and costs O(events retained on disk).
+func scanLogDir(logPath string, logger *slog.Logger) (record.RecordTime, error) {
 	lastTime := record.RecordTime(0)
 	record := new(record.Record)
-	LogPath = filepath.Clean(LogPath)
-	err := filepath.WalkDir(LogPath, func(path string, d fs.DirEntry, err error) error {
-		if d.Type().IsDir() && d.Name() != LogPath {
+	logPath = filepath.Clean(logPath)
+	err := filepath.WalkDir(logPath, func(path string, d fs.DirEntry, err error) error {
+		if d.Type().IsDir() && d.Name() != logPath {
 			return fs.SkipDir // don't descend into subdirs
 		}
 		if err != nil {
@@ -63,7 +79,7 @@ func loadState(LogPath string) (record.RecordTime, error) {
 			if err != nil {
 				return err
 			}
-			log.Printf("loading %s", name)
+			logger.Info("loading log file", "file", name)
 			defer f.Close()
 			scanner := bufio.NewScanner(f)
 			for scanner.Scan() {
@@ -87,9 +103,29 @@ func loadState(LogPath string) (record.RecordTime, error) {
 	return lastTime, nil
 }
 
-func printVersion() {
+// newLogger builds the operator/diagnostic logger from the configured
+// format ("text" or "json", default "text") and level (default "info").
+// This is separate from the sink subsystem, which carries
+// forwarded event payloads rather than operator logs.
+func newLogger(format, level string) *slog.Logger {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	return slog.New(handler)
+}
+
+func printVersion(logger *slog.Logger) {
 	vcsrevision := "unknown"
-	vcsdirty := ""
+	vcsdirty := false
 	vcstime := "unknown"
 	version := "go1.x"
 
@@ -99,9 +135,7 @@ func printVersion() {
 			case "vcs.revision":
 				vcsrevision = v.Value
 			case "vcs.modified":
-				if v.Value == "true" {
-					vcsdirty = " (dirty)"
-				}
+				vcsdirty = v.Value == "true"
 			case "vcs.time":
 				vcstime = v.Value
 			}
@@ -109,7 +143,13 @@ func printVersion() {
 		version = info.GoVersion
 	}
 
-	log.Printf("starting spyderbat-event-forwarder (commit %s%s; %s; %s; %s)", vcsrevision, vcsdirty, vcstime, version, runtime.GOARCH)
+	logger.Info("starting spyderbat-event-forwarder",
+		"commit", vcsrevision,
+		"dirty", vcsdirty,
+		"vcs_time", vcstime,
+		"go_version", version,
+		"arch", runtime.GOARCH,
+	)
 }
 
 func addLinkback(record []byte, cfg *config.Config) []byte {
@@ -122,83 +162,67 @@ func addLinkback(record []byte, cfg *config.Config) []byte {
 
 func main() {
 
-	log.SetFlags(0)
 	configPath := flag.String("c", "config.yaml", "path to config file")
 	flag.Parse()
 
-	printVersion()
-	cfg, err := config.LoadConfig(*configPath)
+	bootstrapLogger := newLogger("text", "info")
+	printVersion(bootstrapLogger)
+	cfg, err := config.LoadConfig(*configPath, bootstrapLogger)
 	if err != nil {
-		log.Fatalf("fatal: %s", err)
+		bootstrapLogger.Error("fatal", "err", err)
+		os.Exit(1)
 	}
 
-	log.Printf("org uid: %s", cfg.OrgUID)
-	log.Printf("api host: %s", cfg.APIHost)
-	log.Printf("log path: %s", cfg.LogPath)
-	log.Printf("local syslog forwarding: %v", cfg.LocalSyslogForwarding)
+	logger := newLogger(cfg.LogFormat, cfg.LogLevel)
+
 	if v, exists := os.LookupEnv("HTTP_PROXY"); exists {
-		log.Printf("http proxy: %s", v)
+		logger.Info("proxy configured", "var", "HTTP_PROXY", "value", v)
 	}
 	if v, exists := os.LookupEnv("HTTPS_PROXY"); exists {
-		log.Printf("https proxy: %s", v)
+		logger.Info("proxy configured", "var", "HTTPS_PROXY", "value", v)
 	}
 	if v, exists := os.LookupEnv("NO_PROXY"); exists {
-		log.Printf("no proxy: %s", v)
+		logger.Info("proxy configured", "var", "NO_PROXY", "value", v)
 	}
 
-	lastTime, err := loadState(cfg.LogPath)
+	lastTime, since, err := loadState(cfg.LogPath, logger)
 	if err != nil {
-		log.Printf("error loading state (ignored): %s", err)
+		logger.Warn("error loading state (ignored)", "err", err)
 	}
 
-	// create a self-rotating logger to write our events to
-	logWriters := []io.Writer{
-		&lumberjack.Logger{
-			Filename:   filepath.Join(cfg.LogPath, "spyderbat_events.log"),
-			MaxSize:    10, // megabytes after which new file is created
-			MaxBackups: 5,  // number of backups
-		},
+	filterEngine, err := filter.New(cfg.FilterExpression)
+	if err != nil {
+		logger.Error("fatal", "err", err)
+		os.Exit(1)
 	}
 
-	if cfg.StdOut {
-		logWriters = append(logWriters, os.Stdout)
-	}
-	var filter = false
-	var reg []*regexp.Regexp
-	if len(cfg.FilterExpression) > 0 {
-		filter = true
-		for i := 0; i < len(cfg.FilterExpression); i++ {
-			regex, err := regexp.Compile(cfg.FilterExpression[i])
-			if err != nil {
-				panic(err)
-			}
-			reg = append(reg, regex)
-		}
+	sinks, err := sink.Build(cfg, logger)
+	if err != nil {
+		logger.Error("fatal", "err", err)
+		os.Exit(1)
 	}
-	if cfg.LocalSyslogForwarding {
-		w, err := syslog.Dial("", "", syslog.LOG_ALERT, "spyderbat-event")
-		if err != nil {
-			log.Printf("syslog forwarding requested, but failed: %s", err)
-		} else {
-			logWriters = append(logWriters, w)
-		}
+	defer sink.CloseAll(sinks)
+
+	var metricsServer *metrics.Server
+	if cfg.MetricsListen != "" {
+		metricsServer = metrics.NewServer(cfg.MetricsListen, logger)
+		metricsServer.Start()
+		defer metricsServer.Close()
 	}
 
-	eventLog := log.New(io.MultiWriter(logWriters...), "", 0)
+	sapi := api.New(cfg, logger)
 
-	sapi := api.New(cfg)
+	var refreshSeq atomic.Uint64
+	nextRefreshID := func() string {
+		return fmt.Sprintf("refresh-%d", refreshSeq.Add(1))
+	}
 
-	_ = sapi.RefreshSources(context.TODO())
+	_ = sapi.RefreshSources(api.WithRequestID(context.TODO(), nextRefreshID()))
 	go func() {
 		t := time.NewTicker(5 * time.Minute)
 		for {
 			<-t.C
-			err := sapi.RefreshSources(context.Background())
-			if err == nil {
-				log.Printf("refreshed sources")
-			} else {
-				log.Printf("error refreshing sources: %s", err)
-			}
+			_ = sapi.RefreshSources(api.WithRequestID(context.Background(), nextRefreshID()))
 		}
 	}()
 
@@ -209,6 +233,7 @@ func main() {
 	st := time.Now().Add(-1 * time.Hour)
 
 	startingUp := true
+	var pollSeq atomic.Uint64
 
 	for {
 		if !startingUp {
@@ -238,11 +263,20 @@ func main() {
 			st = minStart
 		}
 
-		r, err := sapi.SourceDataQuery(context.TODO(), st, et)
+		pollCtx := api.WithRequestID(context.TODO(), fmt.Sprintf("poll-%d", pollSeq.Add(1)))
+
+		queryStart := time.Now()
+		r, err := sapi.SourceDataQuery(pollCtx, st, et, since)
+		metrics.SourceDataQueryDuration.Observe(time.Since(queryStart).Seconds())
 		if err != nil {
-			log.Printf("error querying source data: %v", err)
+			logger.Warn("error querying source data", "err", err)
 			continue
 		}
+		if metricsServer != nil {
+			metricsServer.MarkReady()
+		}
+		// set once any record of this poll fails to reach every sink
+		writeFailed := false
 
 		scanner := bufio.NewScanner(r)
 		recordsRetrieved := 0
@@ -253,6 +287,7 @@ func main() {
 			record := scanner.Bytes()
 			sum := blake2b.Sum256(record)
 			if _, exists := lruCache.Get(sum); exists {
+				metrics.EventsDedupedTotal.Inc()
 				continue // skip duplicates
 			} else {
 				newRecords++
@@ -264,10 +299,6 @@ func main() {
 				continue
 			}
 
-			if last.Time > lastTime {
-				lastTime = last.Time
-			}
-
 			// Augment the record with runtime_details from the muid.
 			// This is harmless in the rare case we pass non-JSON, since we
 			// perform JSON validation next.
@@ -276,33 +307,63 @@ func main() {
 			// Results should always be JSON. Log non-JSON records separately.
 			err = fastjson.ValidateBytes(record)
 			if err == nil {
-				var s = string(record)
-				if filter {
-					for i := 0; i < len(reg); i++ {
-						if reg[i].MatchString(s) {
-							if cfg.Linkback || fastjson.GetString(record, "linkback") != "" {
-								record = addLinkback(record, cfg)
-							}
-							eventLog.Print(string(record))
-						}
-					}
-				} else if !filter {
-					if cfg.Linkback || fastjson.GetString(record, "linkback") != "" {
+				result := filterEngine.Allow(record)
+				if result.Allow {
+					if cfg.Linkback || result.LinkbackRequired || fastjson.GetString(record, "linkback") != "" {
 						record = addLinkback(record, cfg)
 					}
-					eventLog.Print(string(record))
+					if err := sink.WriteAll(context.TODO(), sinks, record); err != nil {
+						logger.Warn("sink write failed", "err", err)
+						writeFailed = true
+					} else {
+						metrics.EventsForwardedTotal.Inc()
+					}
 				}
 			} else {
-				log.Printf("invalid record: %s", r)
+				metrics.EventsInvalidTotal.Inc()
+				logger.Warn("invalid record", "record", string(record))
+			}
+
+			// Only advance the persisted resume point for records that were
+			// either intentionally not forwarded (filtered out, invalid) or
+			// confirmed written to every sink. A sink write failure holds
+			// the cursor at the last record before it for the rest of this
+			// poll, so the failed record is re-requested on restart instead
+			// of being silently dropped.
+			if !writeFailed {
+				if last.Time > lastTime {
+					lastTime = last.Time
+				}
+				if last.ID != "" {
+					since = last.ID
+				}
 			}
 		}
 		r.Close()
 		if err := scanner.Err(); err != nil {
-			log.Printf("error processing records: %s", err)
+			logger.Warn("error processing records", "err", err)
 		}
+
+		metrics.EventsRetrievedTotal.Add(float64(recordsRetrieved))
+		metrics.LastEventAgeSeconds.Set(et.Sub(lastTime.Time()).Seconds())
+		metrics.LRUSize.Set(float64(lruCache.Len()))
+
+		// persist the resume point now that this poll's records have all
+		// been handed to every sink; a batching sink (webhook) may not have
+		// sent them downstream yet when the cursor is saved
+		if err := cursor.Save(cfg.LogPath, &cursor.Cursor{Since: since, Time: lastTime}); err != nil {
+			logger.Warn("error saving cursor", "err", err)
+		}
+
 		if !cfg.StdOut {
 			// only stdout events
-			log.Printf("%d new records, most recent %v ago", newRecords, et.Sub(lastTime.Time()).Round(time.Second))
+			logger.Info("poll complete",
+				"new_records", newRecords,
+				"retrieved", recordsRetrieved,
+				"lag", et.Sub(lastTime.Time()).Round(time.Second),
+				"start", st,
+				"end", et,
+			)
 		}
 	}
 }